@@ -0,0 +1,40 @@
+// Package controller wires ConfigService handlers onto a go-restful
+// WebService.
+package controller
+
+import (
+	"github.com/choerodon/go-register-server/pkg/api/service"
+	"github.com/emicklei/go-restful"
+)
+
+// RegisterConfigRoutes registers the config-server HTTP endpoints backed by
+// cs onto ws.
+func RegisterConfigRoutes(ws *restful.WebService, cs service.ConfigService) {
+	ws.Route(ws.POST("/config/encrypt").To(cs.Encrypt).
+		Doc("encrypt a value for embedding in a Save call").
+		Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON))
+	ws.Route(ws.POST("/config/decrypt").To(cs.Decrypt).
+		Doc("decrypt a previously-encrypted value").
+		Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON))
+	ws.Route(ws.GET("/config/{service}/{version}/watch").To(cs.Watch).
+		Doc("long-poll (or SSE-stream) for a resolved config change").
+		Produces(restful.MIME_JSON, "text/event-stream"))
+	ws.Route(ws.GET("/config/{service}/{profile}/history").To(cs.History).
+		Doc("list recorded revisions for a service/profile, newest first").
+		Produces(restful.MIME_JSON))
+	ws.Route(ws.GET("/config/{service}/{profile}/diff").To(cs.Diff).
+		Doc("diff two recorded revisions").
+		Produces(restful.MIME_JSON))
+	ws.Route(ws.POST("/config/{service}/{profile}/rollback/{revision}").To(cs.Rollback).
+		Doc("restore a prior revision as the live config").
+		Produces(restful.MIME_JSON))
+	ws.Route(ws.POST("/config/variables").To(cs.SaveVariable).
+		Doc("create or overwrite a scoped variable").
+		Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON))
+	ws.Route(ws.GET("/config/variables").To(cs.ListVariables).
+		Doc("list scoped variables, optionally filtered by ?scope=service/profile").
+		Produces(restful.MIME_JSON))
+	ws.Route(ws.GET("/config/clusters").To(cs.Clusters).
+		Doc("list configured clusters and their last-reconciled health").
+		Produces(restful.MIME_JSON))
+}
@@ -0,0 +1,21 @@
+package entity
+
+// PropertySource is one named source of properties within an Environment,
+// matching the shape Spring Cloud Config clients expect from /config/poll.
+type PropertySource struct {
+	Name   string                 `json:"name"`
+	Source map[string]interface{} `json:"source"`
+}
+
+// Environment is the payload ConfigService.Poll returns.
+type Environment struct {
+	Name            string           `json:"name"`
+	Profiles        []string         `json:"profiles"`
+	Version         string           `json:"version"`
+	PropertySources []PropertySource `json:"propertySources"`
+}
+
+// ConfigServerAdditions are extra properties appended to every resolved
+// Environment, e.g. to tell clients which register-server instance served
+// them.
+var ConfigServerAdditions = map[string]interface{}{}
@@ -0,0 +1,41 @@
+package entity
+
+// ApiGatewayServiceName is the well-known service name Save treats
+// specially, splitting zuul route config out into RouteConfigMap.
+const ApiGatewayServiceName = "api-gateway"
+
+// RouteConfigMap is the shared ConfigMap every gateway's zuul routes are
+// stored under, so api-gateway and gateway-helper see the same routes.
+const RouteConfigMap = "zuul-route"
+
+// DefaultProfile is the profile name used when a caller doesn't specify
+// one, stored as application.yml rather than application-{profile}.yml.
+const DefaultProfile = "default"
+
+// ChoerodonVersion is the ConfigMap annotation key holding its revision.
+// Poll reports it as the Environment version and UpdatePolicyCAS compares
+// against it; the ConfigMap operators in pkg/k8s never set or change it.
+const ChoerodonVersion = "choerodon.io/version"
+
+// SaveConfigDTO is the request body accepted by ConfigService.Save.
+type SaveConfigDTO struct {
+	Service      string       `json:"service" validate:"required"`
+	Version      string       `json:"version"`
+	Profile      string       `json:"profile" validate:"required"`
+	Namespace    string       `json:"namespace" validate:"required"`
+	UpdatePolicy UpdatePolicy `json:"updatePolicy" validate:"updatePolicy"`
+	Yaml         string       `json:"yaml" validate:"required"`
+	// Message is an optional human-readable note recorded alongside the
+	// revision this Save produces in the version history.
+	Message string `json:"message,omitempty"`
+	// ExpectedVersion is the ChoerodonVersion this write must match when
+	// UpdatePolicy is UpdatePolicyCAS; mismatches fail with 409 Conflict.
+	// It may also be supplied via the If-Match request header.
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
+	// Cluster selects which configured cluster (see clusters.yaml) this Save
+	// targets, falling back to the local in-cluster store when empty. Save
+	// only reads this from the request body; the X-Cluster-Id header and
+	// ?cluster= parameter are honored on Poll only, via
+	// ConfigServiceImpl.storeForRequest; Watch always reads the local store.
+	Cluster string `json:"cluster,omitempty"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "gopkg.in/go-playground/validator.v9"
+
+// UpdatePolicy controls how Save reconciles an incoming YAML with whatever
+// is already stored in the target ConfigMap.
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyNot leaves an existing ConfigMap untouched.
+	UpdatePolicyNot UpdatePolicy = "not"
+	// UpdatePolicyAdd recursively merges new keys into the existing YAML,
+	// never overwriting a key that's already set (see recursiveAdd).
+	UpdatePolicyAdd UpdatePolicy = "add"
+	// UpdatePolicyOverride replaces the stored YAML outright.
+	UpdatePolicyOverride UpdatePolicy = "override"
+	// UpdatePolicyPatch applies dto.Yaml as an RFC 7396 JSON Merge Patch
+	// against the stored YAML instead of a whole-file overwrite.
+	UpdatePolicyPatch UpdatePolicy = "patch"
+	// UpdatePolicyCAS requires dto.ExpectedVersion to match the ConfigMap's
+	// current ChoerodonVersion annotation, failing the write with 409
+	// Conflict otherwise.
+	UpdatePolicyCAS UpdatePolicy = "cas"
+)
+
+// ValidateUpdatePolicy is registered as the "updatePolicy" validator tag on
+// SaveConfigDTO.UpdatePolicy.
+func ValidateUpdatePolicy(fl validator.FieldLevel) bool {
+	switch UpdatePolicy(fl.Field().String()) {
+	case UpdatePolicyNot, UpdatePolicyAdd, UpdatePolicyOverride, UpdatePolicyPatch, UpdatePolicyCAS:
+		return true
+	default:
+		return false
+	}
+}
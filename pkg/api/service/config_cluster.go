@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/choerodon/go-register-server/pkg/k8s"
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+)
+
+// Clusters handles GET /config/clusters, returning per-cluster health and
+// last-sync time as tracked by the composite store's background reconciler.
+// It responds with an empty list when multi-cluster federation isn't
+// configured.
+func (es *ConfigServiceImpl) Clusters(request *restful.Request, response *restful.Response) {
+	if es.clusterStore == nil {
+		_ = response.WriteAsJson([]*k8s.ClusterHealth{})
+		return
+	}
+	_ = response.WriteAsJson(es.clusterStore.Health())
+}
+
+// storeForRequest picks the ConfigStore to serve a Poll call from, honoring
+// the X-Cluster-Id header (then ?cluster=) and falling back to the default store
+// when multi-cluster federation isn't configured.
+func (es *ConfigServiceImpl) storeForRequest(request *restful.Request) k8s.ConfigMapOperator {
+	if es.clusterStore == nil {
+		return es.configMapOperator
+	}
+	clusterID := request.HeaderParameter("X-Cluster-Id")
+	if clusterID == "" {
+		clusterID = request.QueryParameter("cluster")
+	}
+	store := es.clusterStore.StoreFor(clusterID)
+	glog.V(1).Infof("config request routed to cluster %s", store.ClusterID())
+	return store
+}
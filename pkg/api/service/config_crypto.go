@@ -0,0 +1,106 @@
+package service
+
+import (
+	"github.com/choerodon/go-register-server/pkg/config/crypto"
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+)
+
+const redactedValue = "******"
+
+// EncryptValueDTO is the request/response body for the /config/encrypt and
+// /config/decrypt endpoints.
+type EncryptValueDTO struct {
+	Value string `json:"value" validate:"required"`
+}
+
+// decryptValues walks kvMap in place, replacing every `{cipher}...` value
+// with its plaintext. When rawCipher is true the ciphertext is left as-is,
+// for debugging. It returns the set of keys that were ciphered so the caller
+// can redact them before logging.
+func (es *ConfigServiceImpl) decryptValues(kvMap map[string]interface{}, rawCipher bool) (map[string]bool, error) {
+	ciphered := make(map[string]bool)
+	for k, v := range kvMap {
+		s, ok := v.(string)
+		if !ok || !crypto.HasPrefix(s) {
+			continue
+		}
+		ciphered[k] = true
+		if rawCipher {
+			continue
+		}
+		if es.decryptor == nil {
+			glog.Warningf("Config key %s is ciphered but no crypto backend is configured", k)
+			continue
+		}
+		plain, err := es.decryptor.Decrypt(crypto.TrimPrefix(s))
+		if err != nil {
+			return nil, err
+		}
+		kvMap[k] = plain
+	}
+	return ciphered, nil
+}
+
+// redactCipheredValues returns a copy of kvMap with every key in ciphered
+// replaced by a fixed placeholder, for logging. Only those keys are
+// redacted: another key whose `${...}` placeholder expanded to a decrypted
+// value is logged as expanded.
+func redactCipheredValues(kvMap map[string]interface{}, ciphered map[string]bool) map[string]interface{} {
+	if len(ciphered) == 0 {
+		return kvMap
+	}
+	redacted := make(map[string]interface{}, len(kvMap))
+	for k, v := range kvMap {
+		if ciphered[k] {
+			redacted[k] = redactedValue
+			continue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// Encrypt handles POST /config/encrypt, letting clients cipher a value
+// before embedding it (with the {cipher} prefix) in a Save call.
+func (es *ConfigServiceImpl) Encrypt(request *restful.Request, response *restful.Response) {
+	dto := new(EncryptValueDTO)
+	if err := request.ReadEntity(&dto); err != nil {
+		_ = response.WriteErrorString(400, "invalid EncryptValueDTO")
+		return
+	}
+	if es.decryptor == nil {
+		_ = response.WriteErrorString(500, "no crypto backend is configured")
+		return
+	}
+	ciphertext, err := es.decryptor.Encrypt(dto.Value)
+	if err != nil {
+		glog.Warningf("Encrypt value failed", err)
+		_ = response.WriteErrorString(500, "encrypt failed")
+		return
+	}
+	_ = response.WriteAsJson(&EncryptValueDTO{Value: crypto.CipherPrefix + ciphertext})
+}
+
+// Decrypt handles POST /config/decrypt, the inverse of Encrypt.
+func (es *ConfigServiceImpl) Decrypt(request *restful.Request, response *restful.Response) {
+	dto := new(EncryptValueDTO)
+	if err := request.ReadEntity(&dto); err != nil {
+		_ = response.WriteErrorString(400, "invalid EncryptValueDTO")
+		return
+	}
+	if es.decryptor == nil {
+		_ = response.WriteErrorString(500, "no crypto backend is configured")
+		return
+	}
+	if crypto.HasPrefix(dto.Value) {
+		dto.Value = crypto.TrimPrefix(dto.Value)
+	}
+	plaintext, err := es.decryptor.Decrypt(dto.Value)
+	if err != nil {
+		glog.Warningf("Decrypt value failed", err)
+		_ = response.WriteErrorString(500, "decrypt failed")
+		return
+	}
+	_ = response.WriteAsJson(&EncryptValueDTO{Value: plaintext})
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/choerodon/go-register-server/pkg/api/entity"
+	"github.com/choerodon/go-register-server/pkg/utils"
+	"github.com/emicklei/go-restful"
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+)
+
+const (
+	historyConfigMapSuffix  = "-history"
+	defaultHistoryRetention = 20
+)
+
+// ConfigRevision is one immutable snapshot of a service's configuration,
+// recorded every time Save rewrites the live ConfigMap.
+type ConfigRevision struct {
+	Revision  string `json:"revision" yaml:"revision"`
+	Service   string `json:"service" yaml:"service"`
+	Profile   string `json:"profile" yaml:"profile"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Timestamp int64  `json:"timestamp" yaml:"timestamp"`
+	Author    string `json:"author,omitempty" yaml:"author,omitempty"`
+	Message   string `json:"message,omitempty" yaml:"message,omitempty"`
+	Hash      string `json:"hash" yaml:"hash"`
+	Yaml      string `json:"yaml" yaml:"yaml"`
+}
+
+// ConfigDiffEntry describes a single key that differs between two revisions.
+type ConfigDiffEntry struct {
+	Key      string      `json:"key"`
+	Type     string      `json:"type"` // added, removed, changed
+	OldValue interface{} `json:"oldValue,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"`
+}
+
+func historyConfigMapName(service string) string {
+	return service + historyConfigMapSuffix
+}
+
+func (es *ConfigServiceImpl) readRevisions(service string) (map[string]*ConfigRevision, error) {
+	historyMap := es.configMapOperator.QueryConfigMapByName(historyConfigMapName(service))
+	revisions := make(map[string]*ConfigRevision)
+	if historyMap == nil {
+		return revisions, nil
+	}
+	yamlString := historyMap.Data[utils.ConfigMapProfileKey(entity.DefaultProfile)]
+	if yamlString == "" {
+		return revisions, nil
+	}
+	if err := yaml.Unmarshal([]byte(yamlString), &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (es *ConfigServiceImpl) writeRevisions(service string, namespace string, revisions map[string]*ConfigRevision) error {
+	data, err := yaml.Marshal(revisions)
+	if err != nil {
+		return err
+	}
+	historyName := historyConfigMapName(service)
+	dto := &entity.SaveConfigDTO{
+		Service:      historyName,
+		Profile:      entity.DefaultProfile,
+		Namespace:    namespace,
+		UpdatePolicy: entity.UpdatePolicyOverride,
+		Yaml:         string(data),
+	}
+	if es.configMapOperator.QueryConfigMap(historyName, namespace) == nil {
+		_, err := es.configMapOperator.CreateConfigMap(dto)
+		return err
+	}
+	_, err = es.configMapOperator.UpdateConfigMap(dto)
+	return err
+}
+
+// saveRevision records a new immutable revision for service/profile alongside
+// the live ConfigMap write performed by createOrUpdateConfigMap. Failures here
+// are logged but never fail the caller's Save request, since the live
+// ConfigMap write has already succeeded.
+func (es *ConfigServiceImpl) saveRevision(dto *entity.SaveConfigDTO, yamlContent string, message string) {
+	revisions, err := es.readRevisions(dto.Service)
+	if err != nil {
+		glog.Warningf("Save revision failed when read history", err)
+		return
+	}
+	sum := sha256.Sum256([]byte(yamlContent))
+	hash := hex.EncodeToString(sum[:])
+	revision := &ConfigRevision{
+		Revision:  fmt.Sprintf("%s-%s-%d", dto.Service, dto.Profile, time.Now().UnixNano()),
+		Service:   dto.Service,
+		Profile:   dto.Profile,
+		Namespace: dto.Namespace,
+		Timestamp: time.Now().Unix(),
+		Message:   message,
+		Hash:      hash,
+		Yaml:      yamlContent,
+	}
+	revisions[revision.Revision] = revision
+	trimHistory(revisions, dto.Profile, defaultHistoryRetention)
+	if err := es.writeRevisions(dto.Service, dto.Namespace, revisions); err != nil {
+		glog.Warningf("Save revision failed when write history", err)
+	}
+}
+
+// trimHistory keeps only the most recent `retention` revisions for a profile,
+// deleting older ones in place.
+func trimHistory(revisions map[string]*ConfigRevision, profile string, retention int) {
+	var ordered []*ConfigRevision
+	for _, r := range revisions {
+		if r.Profile == profile {
+			ordered = append(ordered, r)
+		}
+	}
+	if len(ordered) <= retention {
+		return
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp > ordered[j].Timestamp
+	})
+	for _, r := range ordered[retention:] {
+		delete(revisions, r.Revision)
+	}
+}
+
+func (es *ConfigServiceImpl) listRevisions(service string, profile string) ([]*ConfigRevision, error) {
+	revisions, err := es.readRevisions(service)
+	if err != nil {
+		return nil, err
+	}
+	var ordered []*ConfigRevision
+	for _, r := range revisions {
+		if r.Profile == profile {
+			ordered = append(ordered, r)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp > ordered[j].Timestamp
+	})
+	return ordered, nil
+}
+
+func (es *ConfigServiceImpl) findRevision(service string, profile string, revision string) (*ConfigRevision, error) {
+	revisions, err := es.listRevisions(service, profile)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// History handles GET /config/{service}/{profile}/history, listing revisions
+// newest-first with their timestamp and message. saveRevision records no
+// author, so Author is always empty.
+func (es *ConfigServiceImpl) History(request *restful.Request, response *restful.Response) {
+	service := request.PathParameter("service")
+	profile := request.PathParameter("profile")
+	revisions, err := es.listRevisions(service, profile)
+	if err != nil {
+		glog.Warningf("List history failed", err)
+		_ = response.WriteErrorString(500, "list history failed")
+		return
+	}
+	_ = response.WriteAsJson(revisions)
+}
+
+// Diff handles GET /config/{service}/{profile}/diff?from=x&to=y, returning a
+// structured list of added/removed/changed keys between two revisions.
+func (es *ConfigServiceImpl) Diff(request *restful.Request, response *restful.Response) {
+	service := request.PathParameter("service")
+	profile := request.PathParameter("profile")
+	from := request.QueryParameter("from")
+	to := request.QueryParameter("to")
+	if from == "" || to == "" {
+		_ = response.WriteErrorString(400, "from and to are required")
+		return
+	}
+	fromRevision, err := es.findRevision(service, profile, from)
+	if err != nil || fromRevision == nil {
+		_ = response.WriteErrorString(404, "can't find revision "+from)
+		return
+	}
+	toRevision, err := es.findRevision(service, profile, to)
+	if err != nil || toRevision == nil {
+		_ = response.WriteErrorString(404, "can't find revision "+to)
+		return
+	}
+	fromMap := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(fromRevision.Yaml), &fromMap); err != nil {
+		_ = response.WriteErrorString(500, "invalid yaml in revision "+from)
+		return
+	}
+	toMap := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(toRevision.Yaml), &toMap); err != nil {
+		_ = response.WriteErrorString(500, "invalid yaml in revision "+to)
+		return
+	}
+	_ = response.WriteAsJson(diffMaps(fromMap, toMap, ""))
+}
+
+// Rollback handles POST /config/{service}/{profile}/rollback/{revision},
+// atomically restoring a prior revision as the new live ConfigMap content.
+func (es *ConfigServiceImpl) Rollback(request *restful.Request, response *restful.Response) {
+	service := request.PathParameter("service")
+	profile := request.PathParameter("profile")
+	revision := request.PathParameter("revision")
+	target, err := es.findRevision(service, profile, revision)
+	if err != nil {
+		glog.Warningf("Rollback failed when find revision", err)
+		_ = response.WriteErrorString(500, "rollback failed")
+		return
+	}
+	if target == nil {
+		_ = response.WriteErrorString(404, "can't find revision "+revision)
+		return
+	}
+	source := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(target.Yaml), &source); err != nil {
+		_ = response.WriteErrorString(500, "invalid yaml in revision "+revision)
+		return
+	}
+	dto := &entity.SaveConfigDTO{
+		Service:      service,
+		Profile:      profile,
+		Namespace:    target.Namespace,
+		UpdatePolicy: entity.UpdatePolicyOverride,
+		Yaml:         target.Yaml,
+	}
+	if es.createOrUpdateConfigMap(es.storeFor(dto), dto, source, response, false) {
+		es.saveRevision(dto, target.Yaml, "rollback to "+revision)
+	}
+}
+
+// diffMaps recursively walks two parsed YAML trees the same way recursiveAdd
+// does, recording every key that was added, removed, or changed.
+func diffMaps(oldMap map[string]interface{}, newMap map[string]interface{}, prefix string) []ConfigDiffEntry {
+	var entries []ConfigDiffEntry
+	for k, nv := range newMap {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		ov, ok := oldMap[k]
+		if !ok {
+			entries = append(entries, ConfigDiffEntry{Key: key, Type: "added", NewValue: nv})
+			continue
+		}
+		nvMap, nvIsMap := nv.(map[string]interface{})
+		ovMap, ovIsMap := ov.(map[string]interface{})
+		if nvIsMap && ovIsMap {
+			entries = append(entries, diffMaps(ovMap, nvMap, key)...)
+			continue
+		}
+		if !reflect.DeepEqual(ov, nv) {
+			entries = append(entries, ConfigDiffEntry{Key: key, Type: "changed", OldValue: ov, NewValue: nv})
+		}
+	}
+	for k, ov := range oldMap {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if _, ok := newMap[k]; !ok {
+			entries = append(entries, ConfigDiffEntry{Key: key, Type: "removed", OldValue: ov})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffMaps_AddedAndChanged(t *testing.T) {
+	oldMap := map[string]interface{}{"a": "1", "b": "2"}
+	newMap := map[string]interface{}{"a": "1", "b": "3", "c": "4"}
+	entries := diffMaps(oldMap, newMap, "")
+
+	byKey := make(map[string]ConfigDiffEntry)
+	for _, e := range entries {
+		byKey[e.Key] = e
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 diff entries, got %d: %+v", len(entries), entries)
+	}
+	if byKey["b"].Type != "changed" {
+		t.Fatalf("expected b to be changed, got %+v", byKey["b"])
+	}
+	if byKey["c"].Type != "added" {
+		t.Fatalf("expected c to be added, got %+v", byKey["c"])
+	}
+}
+
+func TestDiffMaps_RemovedKey(t *testing.T) {
+	oldMap := map[string]interface{}{"a": "1", "b": "2"}
+	newMap := map[string]interface{}{"a": "1"}
+	entries := diffMaps(oldMap, newMap, "")
+	if len(entries) != 1 || entries[0].Key != "b" || entries[0].Type != "removed" {
+		t.Fatalf("expected b to be removed, got %+v", entries)
+	}
+}
+
+func TestDiffMaps_NestedPrefix(t *testing.T) {
+	oldMap := map[string]interface{}{
+		"db": map[string]interface{}{"host": "old-host"},
+	}
+	newMap := map[string]interface{}{
+		"db": map[string]interface{}{"host": "new-host"},
+	}
+	entries := diffMaps(oldMap, newMap, "")
+	if len(entries) != 1 || entries[0].Key != "db.host" || entries[0].Type != "changed" {
+		t.Fatalf("expected db.host to be changed, got %+v", entries)
+	}
+}
+
+func TestTrimHistory_KeepsNewestWithinRetention(t *testing.T) {
+	revisions := make(map[string]*ConfigRevision)
+	for i := 0; i < 5; i++ {
+		rev := &ConfigRevision{
+			Revision:  string(rune('a' + i)),
+			Profile:   "default",
+			Timestamp: time.Now().Unix() + int64(i),
+		}
+		revisions[rev.Revision] = rev
+	}
+	trimHistory(revisions, "default", 3)
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 revisions to remain, got %d", len(revisions))
+	}
+	for _, want := range []string{"c", "d", "e"} {
+		if _, ok := revisions[want]; !ok {
+			t.Fatalf("expected revision %s to survive trimming, got %+v", want, revisions)
+		}
+	}
+}
+
+func TestTrimHistory_IgnoresOtherProfiles(t *testing.T) {
+	revisions := map[string]*ConfigRevision{
+		"a": {Revision: "a", Profile: "default", Timestamp: 1},
+		"b": {Revision: "b", Profile: "dev", Timestamp: 2},
+	}
+	trimHistory(revisions, "default", 0)
+	if _, ok := revisions["b"]; !ok {
+		t.Fatalf("expected dev-profile revision to be left untouched, got %+v", revisions)
+	}
+	if _, ok := revisions["a"]; ok {
+		t.Fatalf("expected default-profile revision to be trimmed, got %+v", revisions)
+	}
+}
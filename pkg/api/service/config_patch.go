@@ -0,0 +1,57 @@
+package service
+
+import (
+	"github.com/emicklei/go-restful"
+	"github.com/ghodss/yaml"
+)
+
+// DryRunResult is returned instead of writing to the ConfigMap when Save is
+// called with ?dryRun=true, so callers can preview a merge/patch before it
+// is applied.
+type DryRunResult struct {
+	Yaml string            `json:"yaml"`
+	Diff []ConfigDiffEntry `json:"diff"`
+}
+
+func writeDryRunResult(response *restful.Response, oldYaml string, newYaml string) {
+	oldMap := make(map[string]interface{})
+	_ = yaml.Unmarshal([]byte(oldYaml), &oldMap)
+	newMap := make(map[string]interface{})
+	_ = yaml.Unmarshal([]byte(newYaml), &newMap)
+	_ = response.WriteAsJson(&DryRunResult{Yaml: newYaml, Diff: diffMaps(oldMap, newMap, "")})
+}
+
+// applyMergePatch applies an RFC 7396 JSON Merge Patch to oldYaml: a null
+// value in patch deletes the key, an object value merges recursively, and
+// any other value replaces the key outright.
+func applyMergePatch(oldYaml string, patch map[string]interface{}) (string, error) {
+	oldMap := make(map[string]interface{})
+	if oldYaml != "" {
+		if err := yaml.Unmarshal([]byte(oldYaml), &oldMap); err != nil {
+			return "", err
+		}
+	}
+	mergePatch(oldMap, patch)
+	data, err := yaml.Marshal(oldMap)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func mergePatch(target map[string]interface{}, patch map[string]interface{}) {
+	for k, pv := range patch {
+		if pv == nil {
+			delete(target, k)
+			continue
+		}
+		pvMap, pvIsMap := pv.(map[string]interface{})
+		tv, exists := target[k]
+		tvMap, tvIsMap := tv.(map[string]interface{})
+		if pvIsMap && exists && tvIsMap {
+			mergePatch(tvMap, pvMap)
+			continue
+		}
+		target[k] = pv
+	}
+}
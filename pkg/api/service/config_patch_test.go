@@ -0,0 +1,53 @@
+package service
+
+import "testing"
+
+func TestMergePatch_AddsNewKey(t *testing.T) {
+	target := map[string]interface{}{"a": "1"}
+	mergePatch(target, map[string]interface{}{"b": "2"})
+	if target["b"] != "2" {
+		t.Fatalf("expected b to be added, got %v", target)
+	}
+}
+
+func TestMergePatch_NullValueDeletesKey(t *testing.T) {
+	target := map[string]interface{}{"a": "1", "b": "2"}
+	mergePatch(target, map[string]interface{}{"b": nil})
+	if _, ok := target["b"]; ok {
+		t.Fatalf("expected b to be deleted, got %v", target)
+	}
+}
+
+func TestMergePatch_ObjectValueMergesRecursively(t *testing.T) {
+	target := map[string]interface{}{
+		"db": map[string]interface{}{"host": "old-host", "port": "5432"},
+	}
+	mergePatch(target, map[string]interface{}{
+		"db": map[string]interface{}{"host": "new-host"},
+	})
+	db := target["db"].(map[string]interface{})
+	if db["host"] != "new-host" {
+		t.Fatalf("expected host to be replaced, got %v", db)
+	}
+	if db["port"] != "5432" {
+		t.Fatalf("expected port to be preserved, got %v", db)
+	}
+}
+
+func TestMergePatch_NonObjectValueReplacesOutright(t *testing.T) {
+	target := map[string]interface{}{"db": map[string]interface{}{"host": "old-host"}}
+	mergePatch(target, map[string]interface{}{"db": "disabled"})
+	if target["db"] != "disabled" {
+		t.Fatalf("expected db to be replaced outright, got %v", target)
+	}
+}
+
+func TestApplyMergePatch_EmptyOldYaml(t *testing.T) {
+	result, err := applyMergePatch("", map[string]interface{}{"a": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result == "" {
+		t.Fatalf("expected non-empty result")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/choerodon/go-register-server/pkg/api/entity"
 	"github.com/choerodon/go-register-server/pkg/api/metrics"
 	"github.com/choerodon/go-register-server/pkg/api/repository"
+	"github.com/choerodon/go-register-server/pkg/config/crypto"
 	"github.com/choerodon/go-register-server/pkg/embed"
 	"github.com/choerodon/go-register-server/pkg/k8s"
 	"github.com/choerodon/go-register-server/pkg/utils"
@@ -21,24 +22,57 @@ import (
 type ConfigService interface {
 	Save(request *restful.Request, response *restful.Response)
 	Poll(request *restful.Request, response *restful.Response)
+	History(request *restful.Request, response *restful.Response)
+	Diff(request *restful.Request, response *restful.Response)
+	Rollback(request *restful.Request, response *restful.Response)
+	Encrypt(request *restful.Request, response *restful.Response)
+	Decrypt(request *restful.Request, response *restful.Response)
+	Watch(request *restful.Request, response *restful.Response)
+	SaveVariable(request *restful.Request, response *restful.Response)
+	ListVariables(request *restful.Request, response *restful.Response)
+	Clusters(request *restful.Request, response *restful.Response)
 }
 
 type ConfigServiceImpl struct {
 	validate          *validator.Validate
 	appRepo           *repository.ApplicationRepository
 	configMapOperator k8s.ConfigMapOperator
+	decryptor         crypto.Decryptor
+	clusterStore      *k8s.CompositeStore
 }
 
 func NewConfigServiceImpl(appRepo *repository.ApplicationRepository) *ConfigServiceImpl {
+	decryptor, err := crypto.NewDecryptor(embed.Env.ConfigServer.Crypto)
+	if err != nil {
+		glog.Warningf("Config crypto backend disabled", err)
+	}
+	configMapOperator := k8s.NewConfigMapOperator()
 	s := &ConfigServiceImpl{
 		validate:          validator.New(),
 		appRepo:           appRepo,
-		configMapOperator: k8s.NewConfigMapOperator(),
+		configMapOperator: configMapOperator,
+		decryptor:         decryptor,
+		clusterStore:      newClusterStore(configMapOperator),
 	}
 	_ = s.validate.RegisterValidation("updatePolicy", entity.ValidateUpdatePolicy)
 	return s
 }
 
+// newClusterStore wires up multi-cluster federation when clusters.yaml is
+// mounted into the pod, and is a no-op (returning nil) otherwise so Poll and
+// Save keep working against the local cluster only.
+func newClusterStore(local k8s.ConfigMapOperator) *k8s.CompositeStore {
+	if embed.Env.ConfigServer.ClustersFile == "" {
+		return nil
+	}
+	remotes, err := k8s.LoadRemoteClusterStores(embed.Env.ConfigServer.ClustersFile)
+	if err != nil {
+		glog.Warningf("Load remote cluster stores failed, falling back to local cluster only", err)
+		return nil
+	}
+	return k8s.NewCompositeStore(local, remotes, 0)
+}
+
 func (es *ConfigServiceImpl) Save(request *restful.Request, response *restful.Response) {
 	metrics.RequestCount.With(prometheus.Labels{"path": request.Request.RequestURI}).Inc()
 	dto := new(entity.SaveConfigDTO)
@@ -62,6 +96,14 @@ func (es *ConfigServiceImpl) Save(request *restful.Request, response *restful.Re
 		return
 	}
 
+	dryRun := request.QueryParameter("dryRun") == "true"
+	broadcast := es.clusterStore != nil && request.QueryParameter("broadcast") == "true" && !dryRun
+	if broadcast && dto.UpdatePolicy != entity.UpdatePolicyOverride {
+		glog.Warningf("Save config failed, broadcast only supports the override update policy, got %s", dto.UpdatePolicy)
+		_ = response.WriteErrorString(400, "broadcast only supports the override update policy")
+		return
+	}
+
 	if dto.Service == entity.ApiGatewayServiceName {
 		gb, rb, rm, err := separateRoute(source)
 		if err != nil {
@@ -78,48 +120,115 @@ func (es *ConfigServiceImpl) Save(request *restful.Request, response *restful.Re
 			UpdatePolicy: dto.UpdatePolicy,
 			Yaml:         rb,
 		}
-		es.createOrUpdateConfigMap(routeDTO, rm, response)
+		if broadcast {
+			if err := es.clusterStore.Broadcast(routeDTO); err != nil {
+				glog.Warningf("Save config failed when broadcast route to clusters", err)
+				_ = response.WriteErrorString(500, "broadcast to clusters failed")
+				return
+			}
+			es.saveRevision(routeDTO, rb, dto.Message)
+		} else if !dryRun && es.createOrUpdateConfigMap(es.storeFor(routeDTO), routeDTO, rm, response, false) {
+			es.saveRevision(routeDTO, rb, dto.Message)
+		}
 	}
-	es.createOrUpdateConfigMap(dto, source, response)
-}
 
-func (es *ConfigServiceImpl) createOrUpdateConfigMap(dto *entity.SaveConfigDTO, source map[string]interface{}, response *restful.Response) {
-	queryConfigMap := es.configMapOperator.QueryConfigMap(dto.Service, dto.Namespace)
-	if queryConfigMap == nil {
-		_, err := es.configMapOperator.CreateConfigMap(dto)
-		if err != nil {
-			glog.Warningf("Save config failed when create configMap", err)
-			_ = response.WriteErrorString(500, "create configMap failed")
+	if broadcast {
+		if err := es.clusterStore.Broadcast(dto); err != nil {
+			glog.Warningf("Save config failed when broadcast to clusters", err)
+			_ = response.WriteErrorString(500, "broadcast to clusters failed")
 			return
 		}
+		es.saveRevision(dto, dto.Yaml, dto.Message)
+		return
+	}
+
+	if ifMatch := request.HeaderParameter("If-Match"); ifMatch != "" && dto.ExpectedVersion == "" {
+		dto.ExpectedVersion = ifMatch
+	}
+	if es.createOrUpdateConfigMap(es.storeFor(dto), dto, source, response, dryRun) && !dryRun {
+		es.saveRevision(dto, dto.Yaml, dto.Message)
+	}
+}
+
+// storeFor picks which cluster's ConfigStore a write targets: dto.Cluster
+// when multi-cluster federation is configured, otherwise the local
+// in-cluster operator.
+func (es *ConfigServiceImpl) storeFor(dto *entity.SaveConfigDTO) k8s.ConfigMapOperator {
+	if es.clusterStore == nil || dto.Cluster == "" {
+		return es.configMapOperator
 	}
+	return es.clusterStore.StoreFor(dto.Cluster)
+}
+
+// createOrUpdateConfigMap writes dto's yaml to store, reporting any failure
+// (a rejected CAS write, a merge/patch error, or a k8s write error) to
+// response itself. It returns whether a ConfigMap write actually happened,
+// so callers know whether it's safe to record a new revision in history.
+func (es *ConfigServiceImpl) createOrUpdateConfigMap(store k8s.ConfigMapOperator, dto *entity.SaveConfigDTO, source map[string]interface{}, response *restful.Response, dryRun bool) bool {
+	queryConfigMap := store.QueryConfigMap(dto.Service, dto.Namespace)
 	if queryConfigMap != nil && dto.UpdatePolicy == entity.UpdatePolicyNot {
 		glog.Infof("configMap %s is already exist", dto.Service)
 		_ = response.WriteErrorString(304, "configMap is already exist")
-		return
+		return false
+	}
+	if queryConfigMap != nil && dto.UpdatePolicy == entity.UpdatePolicyCAS {
+		currentVersion := queryConfigMap.Annotations[entity.ChoerodonVersion]
+		if dto.ExpectedVersion != currentVersion {
+			glog.Infof("configMap %s version mismatch, expected %s but was %s", dto.Service, dto.ExpectedVersion, currentVersion)
+			_ = response.WriteErrorString(409, "configMap version mismatch")
+			return false
+		}
+	}
+
+	oldYaml := ""
+	profileKey := utils.ConfigMapProfileKey(dto.Profile)
+	if queryConfigMap != nil {
+		oldYaml = queryConfigMap.Data[profileKey]
 	}
 
-	if dto.UpdatePolicy == entity.UpdatePolicyAdd {
-		profileKey := utils.ConfigMapProfileKey(dto.Profile)
-		oldYaml := queryConfigMap.Data[profileKey]
+	switch dto.UpdatePolicy {
+	case entity.UpdatePolicyAdd:
 		if oldYaml != "" {
 			newYaml, err := addProperty(oldYaml, source)
 			if err != nil {
 				glog.Warningf("Save config failed when merge yaml", err)
 				_ = response.WriteErrorString(500, "merge yaml failed")
-				return
+				return false
 			}
 			dto.Yaml = newYaml
 		}
+	case entity.UpdatePolicyPatch:
+		newYaml, err := applyMergePatch(oldYaml, source)
+		if err != nil {
+			glog.Warningf("Save config failed when apply patch", err)
+			_ = response.WriteErrorString(500, "apply patch failed")
+			return false
+		}
+		dto.Yaml = newYaml
+	}
+
+	if dryRun {
+		writeDryRunResult(response, oldYaml, dto.Yaml)
+		return false
+	}
+
+	if queryConfigMap == nil {
+		_, err := store.CreateConfigMap(dto)
+		if err != nil {
+			glog.Warningf("Save config failed when create configMap", err)
+			_ = response.WriteErrorString(500, "create configMap failed")
+			return false
+		}
 	}
 	if dto.UpdatePolicy != entity.UpdatePolicyNot {
-		_, err := es.configMapOperator.UpdateConfigMap(dto)
+		_, err := store.UpdateConfigMap(dto)
 		if err != nil {
 			glog.Warningf("Save config failed when update configMap", err)
 			_ = response.WriteErrorString(500, "update configMap failed")
-			return
+			return false
 		}
 	}
+	return true
 }
 
 func (es *ConfigServiceImpl) Poll(request *restful.Request, response *restful.Response) {
@@ -134,14 +243,22 @@ func (es *ConfigServiceImpl) Poll(request *restful.Request, response *restful.Re
 		_ = response.WriteErrorString(400, "version is empty")
 		return
 	}
-	kvMap, configMapVersion, err := es.getConfigFromConfigMap(service, version)
+	store := es.storeForRequest(request)
+	kvMap, configMapVersion, err := es.getConfigFromConfigMap(store, service, version)
 	if err != nil {
 		_ = response.WriteErrorString(404, "can't find correct configMap")
 		glog.Warningf("Get config from configMap failed, service: %s", service, err)
 		return
 	}
+	rawCipher := request.QueryParameter("rawCipher") == "true"
+	cipheredKeys, err := es.decryptValues(kvMap, rawCipher)
+	if err != nil {
+		_ = response.WriteErrorString(500, "decrypt config failed")
+		glog.Warningf("Decrypt config failed, service: %s", service, err)
+		return
+	}
 	if isGateway(service) {
-		routeMap, _, err := es.getConfigFromConfigMap(entity.RouteConfigMap, version)
+		routeMap, _, err := es.getConfigFromConfigMap(store, entity.RouteConfigMap, version)
 		if err != nil {
 			_ = response.WriteErrorString(404, "can't find zuul-route configMap")
 			glog.Warningf("Get zuul-route from configMap failed", err)
@@ -157,6 +274,11 @@ func (es *ConfigServiceImpl) Poll(request *restful.Request, response *restful.Re
 			kvMap[k] = v
 		}
 	}
+	if err := es.newResolver().ExpandAll(kvMap); err != nil {
+		glog.Warningf("Expand config placeholders failed, service: %s", service, err)
+		_ = response.WriteErrorString(500, "expand config placeholders failed")
+		return
+	}
 	es.appendConfigServerAddition(kvMap)
 	env := &entity.Environment{
 		Name:            service,
@@ -165,7 +287,7 @@ func (es *ConfigServiceImpl) Poll(request *restful.Request, response *restful.Re
 		PropertySources: []entity.PropertySource{{Name: service + "-" + version + "-" + configMapVersion, Source: kvMap}},
 	}
 	if embed.Env.ConfigServer.Log {
-		printConfig, _ := json.MarshalIndent(kvMap, "", "  ")
+		printConfig, _ := json.MarshalIndent(redactCipheredValues(kvMap, cipheredKeys), "", "  ")
 		glog.Infof("%s-%v pulled config: %s", service, version, printConfig)
 	} else {
 		glog.Infof("%s-%v pulled config", service, version)
@@ -182,9 +304,9 @@ func (es *ConfigServiceImpl) appendConfigServerAddition(kvMap map[string]interfa
 	}
 }
 
-func (es *ConfigServiceImpl) getConfigFromConfigMap(service string, version string) (map[string]interface{}, string, error) {
+func (es *ConfigServiceImpl) getConfigFromConfigMap(store k8s.ConfigMapOperator, service string, version string) (map[string]interface{}, string, error) {
 	source := make(map[string]interface{})
-	configMap := es.configMapOperator.QueryConfigMapByName(service)
+	configMap := store.QueryConfigMapByName(service)
 	if configMap == nil {
 		return nil, "", errors.New("can't find configMap")
 	}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/choerodon/go-register-server/pkg/api/entity"
+	"github.com/choerodon/go-register-server/pkg/config/resolver"
+	"github.com/choerodon/go-register-server/pkg/utils"
+	"github.com/emicklei/go-restful"
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+)
+
+const scopedVariableConfigMapName = "scoped-variable"
+
+// ScopedVariableDTO is a single (service, profile, key) -> value entry in
+// the shared ScopedVariable store. Entries are read only through
+// `${scope:service/profile/key}` placeholders; an entry does not replace a
+// per-service property of the same key.
+type ScopedVariableDTO struct {
+	Service   string `json:"service" validate:"required"`
+	Profile   string `json:"profile" validate:"required"`
+	Key       string `json:"key" validate:"required"`
+	Value     string `json:"value"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// scopedVariables is keyed service -> profile -> key -> value.
+type scopedVariables map[string]map[string]map[string]string
+
+func (es *ConfigServiceImpl) readScopedVariables() (scopedVariables, error) {
+	vars := make(scopedVariables)
+	configMap := es.configMapOperator.QueryConfigMapByName(scopedVariableConfigMapName)
+	if configMap == nil {
+		return vars, nil
+	}
+	yamlString := configMap.Data[utils.ConfigMapProfileKey(entity.DefaultProfile)]
+	if yamlString == "" {
+		return vars, nil
+	}
+	if err := yaml.Unmarshal([]byte(yamlString), &vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func (es *ConfigServiceImpl) writeScopedVariables(vars scopedVariables, namespace string) error {
+	data, err := yaml.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	dto := &entity.SaveConfigDTO{
+		Service:      scopedVariableConfigMapName,
+		Profile:      entity.DefaultProfile,
+		Namespace:    namespace,
+		UpdatePolicy: entity.UpdatePolicyOverride,
+		Yaml:         string(data),
+	}
+	if es.configMapOperator.QueryConfigMap(scopedVariableConfigMapName, namespace) == nil {
+		_, err := es.configMapOperator.CreateConfigMap(dto)
+		return err
+	}
+	_, err = es.configMapOperator.UpdateConfigMap(dto)
+	return err
+}
+
+// scopeLookup adapts readScopedVariables to the resolver.ScopeLookup shape
+// used to expand `${scope:service/profile/key}` placeholders.
+func (es *ConfigServiceImpl) scopeLookup(service string, profile string, key string) (string, bool) {
+	vars, err := es.readScopedVariables()
+	if err != nil {
+		glog.Warningf("Resolve scoped variable failed", err)
+		return "", false
+	}
+	byProfile, ok := vars[service]
+	if !ok {
+		return "", false
+	}
+	byKey, ok := byProfile[profile]
+	if !ok {
+		return "", false
+	}
+	v, ok := byKey[key]
+	return v, ok
+}
+
+func (es *ConfigServiceImpl) newResolver() *resolver.Resolver {
+	return resolver.New(es.scopeLookup)
+}
+
+// SaveVariable handles POST /config/variables, creating or overwriting a
+// single scoped variable entry.
+func (es *ConfigServiceImpl) SaveVariable(request *restful.Request, response *restful.Response) {
+	dto := new(ScopedVariableDTO)
+	if err := request.ReadEntity(&dto); err != nil {
+		_ = response.WriteErrorString(400, "invalid ScopedVariableDTO")
+		return
+	}
+	if err := es.validate.Struct(dto); err != nil {
+		_ = response.WriteErrorString(400, "invalid ScopedVariableDTO")
+		return
+	}
+	vars, err := es.readScopedVariables()
+	if err != nil {
+		glog.Warningf("Save scoped variable failed when read existing variables", err)
+		_ = response.WriteErrorString(500, "save scoped variable failed")
+		return
+	}
+	if vars[dto.Service] == nil {
+		vars[dto.Service] = make(map[string]map[string]string)
+	}
+	if vars[dto.Service][dto.Profile] == nil {
+		vars[dto.Service][dto.Profile] = make(map[string]string)
+	}
+	vars[dto.Service][dto.Profile][dto.Key] = dto.Value
+	if err := es.writeScopedVariables(vars, dto.Namespace); err != nil {
+		glog.Warningf("Save scoped variable failed", err)
+		_ = response.WriteErrorString(500, "save scoped variable failed")
+		return
+	}
+	_ = response.WriteAsJson(dto)
+}
+
+// ListVariables handles GET /config/variables?scope=service/profile,
+// returning every key/value pair in that scope.
+func (es *ConfigServiceImpl) ListVariables(request *restful.Request, response *restful.Response) {
+	scope := request.QueryParameter("scope")
+	vars, err := es.readScopedVariables()
+	if err != nil {
+		glog.Warningf("List scoped variables failed", err)
+		_ = response.WriteErrorString(500, "list scoped variables failed")
+		return
+	}
+	if scope == "" {
+		_ = response.WriteAsJson(vars)
+		return
+	}
+	parts := strings.SplitN(scope, "/", 2)
+	if len(parts) != 2 {
+		_ = response.WriteErrorString(400, "scope must be service/profile")
+		return
+	}
+	byProfile, ok := vars[parts[0]]
+	if !ok {
+		_ = response.WriteAsJson(map[string]string{})
+		return
+	}
+	_ = response.WriteAsJson(byProfile[parts[1]])
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choerodon/go-register-server/pkg/api/entity"
+	"github.com/choerodon/go-register-server/pkg/embed"
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+)
+
+const defaultWatchTimeout = 30 * time.Second
+
+// resolveEnvironment builds the same entity.Environment that Poll returns,
+// plus a stable content hash of its PropertySources, so Watch can tell
+// whether the resolved config actually changed. It always reads from the
+// local in-cluster store; the X-Cluster-Id header and ?cluster= parameter
+// are not applied here.
+func (es *ConfigServiceImpl) resolveEnvironment(service string, version string) (*entity.Environment, string, error) {
+	kvMap, configMapVersion, err := es.getConfigFromConfigMap(es.configMapOperator, service, version)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := es.decryptValues(kvMap, false); err != nil {
+		return nil, "", err
+	}
+	if isGateway(service) {
+		routeMap, _, err := es.getConfigFromConfigMap(es.configMapOperator, entity.RouteConfigMap, version)
+		if err != nil {
+			return nil, "", err
+		}
+		for k := range kvMap {
+			if strings.HasPrefix(k, "zuul.routes.") {
+				delete(kvMap, k)
+			}
+		}
+		for k, v := range routeMap {
+			kvMap[k] = v
+		}
+	}
+	if err := es.newResolver().ExpandAll(kvMap); err != nil {
+		return nil, "", err
+	}
+	es.appendConfigServerAddition(kvMap)
+	env := &entity.Environment{
+		Name:            service,
+		Version:         configMapVersion,
+		Profiles:        []string{version},
+		PropertySources: []entity.PropertySource{{Name: service + "-" + version + "-" + configMapVersion, Source: kvMap}},
+	}
+	return env, hashKvMap(kvMap), nil
+}
+
+// hashKvMap computes a stable hash of a resolved kvMap, independent of Go's
+// randomized map iteration order.
+func hashKvMap(kvMap map[string]interface{}) string {
+	keys := make([]string, 0, len(kvMap))
+	for k := range kvMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ordered := make([]interface{}, 0, len(keys)*2)
+	for _, k := range keys {
+		ordered = append(ordered, k, kvMap[k])
+	}
+	data, _ := json.Marshal(ordered)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Watch handles GET /config/{service}/{version}/watch?state=<hash>. If the
+// caller's state hash no longer matches the current config, it responds
+// immediately. Otherwise it holds the connection open, subscribed to the
+// ConfigMap informer, until the config changes or a timeout elapses, so
+// clients get push-style updates without polling on a timer.
+func (es *ConfigServiceImpl) Watch(request *restful.Request, response *restful.Response) {
+	service := request.PathParameter("service")
+	version := request.PathParameter("version")
+	if service == "" || version == "" {
+		_ = response.WriteErrorString(400, "service and version are required")
+		return
+	}
+	clientState := request.QueryParameter("state")
+
+	env, hash, err := es.resolveEnvironment(service, version)
+	if err != nil {
+		_ = response.WriteErrorString(404, "can't find correct configMap")
+		glog.Warningf("Watch config failed, service: %s", service, err)
+		return
+	}
+
+	if request.Request.Header.Get("Accept") == "text/event-stream" {
+		es.watchSSE(request, response, service, version, clientState, env, hash)
+		return
+	}
+
+	if clientState != hash {
+		es.writeWatchResult(response, env, hash)
+		return
+	}
+
+	changed, changedEnv, changedHash := es.waitForChange(request.Request.Context(), service, version, hash, watchTimeout())
+	if !changed {
+		_ = response.WriteHeader(304)
+		return
+	}
+	es.writeWatchResult(response, changedEnv, changedHash)
+}
+
+func (es *ConfigServiceImpl) writeWatchResult(response *restful.Response, env *entity.Environment, hash string) {
+	response.AddHeader("X-Config-State", hash)
+	if err := response.WriteAsJson(env); err != nil {
+		glog.Warningf("Watch config write as json failed", err)
+	}
+}
+
+// watchSSE streams successive Environment snapshots over a single
+// text/event-stream connection, so Spring Cloud Bus-style clients can
+// subscribe without polling.
+func (es *ConfigServiceImpl) watchSSE(request *restful.Request, response *restful.Response, service string, version string, clientState string, env *entity.Environment, hash string) {
+	flusher, ok := response.ResponseWriter.(http.Flusher)
+	if !ok {
+		_ = response.WriteErrorString(500, "streaming unsupported")
+		return
+	}
+	response.AddHeader("Content-Type", "text/event-stream")
+	response.AddHeader("Cache-Control", "no-cache")
+	response.AddHeader("Connection", "keep-alive")
+	response.ResponseWriter.WriteHeader(200)
+
+	writeEvent := func(env *entity.Environment, hash string) {
+		data, _ := json.Marshal(env)
+		fmt.Fprintf(response.ResponseWriter, "id: %s\ndata: %s\n\n", hash, data)
+		flusher.Flush()
+	}
+
+	if clientState != hash {
+		writeEvent(env, hash)
+	}
+	lastHash := hash
+	ctx := request.Request.Context()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		changed, changedEnv, changedHash := es.waitForChange(ctx, service, version, lastHash, watchTimeout())
+		if ctx.Err() != nil {
+			return
+		}
+		if !changed {
+			fmt.Fprintf(response.ResponseWriter, ": timeout\n\n")
+			flusher.Flush()
+			continue
+		}
+		writeEvent(changedEnv, changedHash)
+		lastHash = changedHash
+	}
+}
+
+// waitForChange blocks on the k8s ConfigMap informer for service's backing
+// ConfigMap (and, for gateways, the shared route ConfigMap) until the
+// resolved config hash differs from lastHash, timeout elapses, or ctx is
+// done (the client disconnected).
+func (es *ConfigServiceImpl) waitForChange(ctx context.Context, service string, version string, lastHash string, timeout time.Duration) (bool, *entity.Environment, string) {
+	events, stop := es.configMapOperator.WatchConfigMap(service)
+	defer stop()
+	// routeEvents stays nil (never ready) for non-gateway services.
+	var routeEvents <-chan struct{}
+	if isGateway(service) {
+		var stopRoute func()
+		routeEvents, stopRoute = es.configMapOperator.WatchConfigMap(entity.RouteConfigMap)
+		defer stopRoute()
+	}
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-events:
+		case <-routeEvents:
+		case <-deadline:
+			return false, nil, ""
+		case <-ctx.Done():
+			return false, nil, ""
+		}
+		env, hash, err := es.resolveEnvironment(service, version)
+		if err != nil {
+			glog.Warningf("Watch config failed while resolving change, service: %s", service, err)
+			continue
+		}
+		if hash == lastHash {
+			continue
+		}
+		return true, env, hash
+	}
+}
+
+func watchTimeout() time.Duration {
+	if embed.Env.ConfigServer.WatchTimeoutSeconds <= 0 {
+		return defaultWatchTimeout
+	}
+	return time.Duration(embed.Env.ConfigServer.WatchTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// aesGCMDecryptor implements Decryptor with AES-GCM and a local symmetric
+// key, for deployments that don't run a KMS.
+type aesGCMDecryptor struct {
+	gcm cipher.AEAD
+}
+
+func newAESGCMDecryptor(key string) (Decryptor, error) {
+	if key == "" {
+		return nil, errors.New("crypto: local key is required for the local backend")
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCMDecryptor{gcm: gcm}, nil
+}
+
+func (d *aesGCMDecryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, d.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := d.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (d *aesGCMDecryptor) Decrypt(ciphertext string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := d.gcm.NonceSize()
+	if len(raw) < nonceSize {
+		return "", errors.New("crypto: ciphertext too short")
+	}
+	nonce, sealed := raw[:nonceSize], raw[nonceSize:]
+	plain, err := d.gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,32 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// awsKMSDecryptor is the Decryptor type for the "kms" backend. It holds no
+// AWS client: newAWSKMSDecryptor always returns an error, and its Encrypt
+// and Decrypt always fail.
+type awsKMSDecryptor struct {
+	region string
+	keyID  string
+}
+
+// newAWSKMSDecryptor is not yet wired to a real AWS KMS client. It fails fast
+// at construction rather than advertising a working "kms" backend that would
+// only break later, mid-Poll, on the first ciphered value it touches.
+func newAWSKMSDecryptor(region string, keyID string) (Decryptor, error) {
+	if region == "" || keyID == "" {
+		return nil, errors.New("crypto: kms region and keyID are required for the kms backend")
+	}
+	return nil, fmt.Errorf("crypto: aws kms backend (region %s, key %s) is not yet wired to an aws client", region, keyID)
+}
+
+func (d *awsKMSDecryptor) Encrypt(plaintext string) (string, error) {
+	return "", fmt.Errorf("crypto: aws kms encrypt not yet wired to an aws client")
+}
+
+func (d *awsKMSDecryptor) Decrypt(ciphertext string) (string, error) {
+	return "", fmt.Errorf("crypto: aws kms decrypt not yet wired to an aws client")
+}
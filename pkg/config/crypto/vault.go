@@ -0,0 +1,33 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// vaultTransitDecryptor is the Decryptor type for the "vault" backend. It
+// holds no Vault client: newVaultTransitDecryptor always returns an error,
+// and its Encrypt and Decrypt always fail.
+type vaultTransitDecryptor struct {
+	addr string
+	path string
+	key  string
+}
+
+// newVaultTransitDecryptor is not yet wired to a real Vault client. It fails
+// fast at construction rather than advertising a working "vault" backend that
+// would only break later, mid-Poll, on the first ciphered value it touches.
+func newVaultTransitDecryptor(addr string, path string, key string) (Decryptor, error) {
+	if addr == "" || key == "" {
+		return nil, errors.New("crypto: vault addr and key are required for the vault backend")
+	}
+	return nil, fmt.Errorf("crypto: vault transit backend (%s/%s) is not yet wired to a vault client", path, key)
+}
+
+func (d *vaultTransitDecryptor) Encrypt(plaintext string) (string, error) {
+	return "", fmt.Errorf("crypto: vault transit encrypt not yet wired to a vault client")
+}
+
+func (d *vaultTransitDecryptor) Decrypt(ciphertext string) (string, error) {
+	return "", fmt.Errorf("crypto: vault transit decrypt not yet wired to a vault client")
+}
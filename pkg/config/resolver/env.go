@@ -0,0 +1,7 @@
+package resolver
+
+import "os"
+
+func osLookupEnv(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
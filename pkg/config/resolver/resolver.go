@@ -0,0 +1,179 @@
+// Package resolver expands `${...}` property placeholders in a resolved
+// config kvMap, so services can reference other properties and scoped
+// variables instead of duplicating values across every per-service YAML.
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const defaultMaxDepth = 10
+
+var placeholderPattern = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+// errUnresolved marks a plain-key placeholder (not `env:`/`scope:`) that
+// couldn't be resolved - missing key, a reference cycle, or exceeding
+// MaxDepth. Unlike env/scope failures, these are left as literal `${...}`
+// text rather than failing the whole ExpandAll call: real service YAMLs
+// routinely contain placeholder syntax never meant for server-side
+// resolution (Spring's `${random.value}`, `${spring.application.name}`, log
+// patterns, ...).
+var errUnresolved = errors.New("resolver: unresolved placeholder")
+
+// ScopeLookup resolves a `${scope:service/profile/key}` placeholder against
+// the ScopedVariable store.
+type ScopeLookup func(service string, profile string, key string) (string, bool)
+
+// EnvLookup resolves a `${env:VAR:default}` placeholder against the
+// register-server process environment.
+type EnvLookup func(name string) (string, bool)
+
+// Resolver expands placeholders found in a flattened kvMap, the same shape
+// produced by utils.ConvertRecursiveMapToSingleMap.
+type Resolver struct {
+	MaxDepth int
+	Env      EnvLookup
+	Scope    ScopeLookup
+}
+
+// New builds a Resolver with the given scope lookup and the process
+// environment as its env lookup.
+func New(scope ScopeLookup) *Resolver {
+	return &Resolver{
+		MaxDepth: defaultMaxDepth,
+		Env:      osLookupEnv,
+		Scope:    scope,
+	}
+}
+
+// ExpandAll rewrites every string value in kvMap that contains a `${...}`
+// placeholder, in place. Placeholders resolve against the values kvMap held
+// before the call, so the result doesn't depend on iteration order.
+func (r *Resolver) ExpandAll(kvMap map[string]interface{}) error {
+	maxDepth := r.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	source := make(map[string]interface{}, len(kvMap))
+	for k, v := range kvMap {
+		source[k] = v
+	}
+	for k, v := range source {
+		s, ok := v.(string)
+		if !ok || !strings.Contains(s, "${") {
+			continue
+		}
+		expanded, err := r.expand(s, source, map[string]bool{k: true}, maxDepth)
+		if err != nil && !errors.Is(err, errUnresolved) {
+			return fmt.Errorf("resolver: key %s: %w", k, err)
+		}
+		kvMap[k] = expanded
+	}
+	return nil
+}
+
+func (r *Resolver) expand(value string, kvMap map[string]interface{}, inProgress map[string]bool, depth int) (string, error) {
+	if depth <= 0 {
+		return value, errUnresolved
+	}
+	var expandErr error
+	unresolved := false
+	result := placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+		expr := match[2 : len(match)-1]
+		resolved, err := r.resolveOne(expr, kvMap, inProgress, depth-1)
+		if err != nil {
+			if errors.Is(err, errUnresolved) {
+				unresolved = true
+				return match
+			}
+			expandErr = err
+			return match
+		}
+		return resolved
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	// Report an unresolved placeholder upward, so a key whose value can't be
+	// fully resolved is itself left as literal text by the referencing key.
+	if unresolved {
+		return result, errUnresolved
+	}
+	return result, nil
+}
+
+func (r *Resolver) resolveOne(expr string, kvMap map[string]interface{}, inProgress map[string]bool, depth int) (string, error) {
+	switch {
+	case strings.HasPrefix(expr, "env:"):
+		return r.resolveEnv(strings.TrimPrefix(expr, "env:"))
+	case strings.HasPrefix(expr, "scope:"):
+		return r.resolveScope(strings.TrimPrefix(expr, "scope:"))
+	default:
+		return r.resolveKey(expr, kvMap, inProgress, depth)
+	}
+}
+
+func (r *Resolver) resolveEnv(expr string) (string, error) {
+	name := expr
+	def := ""
+	hasDefault := false
+	if idx := strings.Index(expr, ":"); idx >= 0 {
+		name = expr[:idx]
+		def = expr[idx+1:]
+		hasDefault = true
+	}
+	if r.Env != nil {
+		if v, ok := r.Env(name); ok {
+			return v, nil
+		}
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return "", fmt.Errorf("env variable %s is not set and has no default", name)
+}
+
+func (r *Resolver) resolveScope(expr string) (string, error) {
+	parts := strings.SplitN(expr, "/", 3)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("scope placeholder %q must be service/profile/key", expr)
+	}
+	if r.Scope == nil {
+		return "", fmt.Errorf("no scoped variable store configured")
+	}
+	v, ok := r.Scope(parts[0], parts[1], parts[2])
+	if !ok {
+		return "", fmt.Errorf("scoped variable %s not found", expr)
+	}
+	return v, nil
+}
+
+// resolveKey resolves a plain (non env:/scope:) placeholder against kvMap.
+// Any failure here - an unknown key, a reference cycle, or exceeding
+// MaxDepth - is reported as errUnresolved so the caller leaves the
+// placeholder as literal text instead of failing the whole Poll/Watch.
+func (r *Resolver) resolveKey(key string, kvMap map[string]interface{}, inProgress map[string]bool, depth int) (string, error) {
+	if inProgress[key] {
+		return "", errUnresolved
+	}
+	raw, ok := kvMap[key]
+	if !ok {
+		return "", errUnresolved
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return fmt.Sprintf("%v", raw), nil
+	}
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	inProgress[key] = true
+	defer delete(inProgress, key)
+	return r.expand(s, kvMap, inProgress, depth)
+}
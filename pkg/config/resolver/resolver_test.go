@@ -0,0 +1,116 @@
+package resolver
+
+import "testing"
+
+func newTestResolver() *Resolver {
+	return &Resolver{
+		MaxDepth: defaultMaxDepth,
+		Env: func(name string) (string, bool) {
+			if name == "HOST" {
+				return "env-host", true
+			}
+			return "", false
+		},
+		Scope: func(service string, profile string, key string) (string, bool) {
+			if service == "svc" && profile == "default" && key == "port" {
+				return "8080", true
+			}
+			return "", false
+		},
+	}
+}
+
+func TestExpandAll_KeyReference(t *testing.T) {
+	kvMap := map[string]interface{}{
+		"a": "value-a",
+		"b": "${a}-suffix",
+	}
+	if err := newTestResolver().ExpandAll(kvMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kvMap["b"] != "value-a-suffix" {
+		t.Fatalf("expected key reference to expand, got %v", kvMap["b"])
+	}
+}
+
+func TestExpandAll_EnvWithAndWithoutDefault(t *testing.T) {
+	kvMap := map[string]interface{}{
+		"a": "${env:HOST}",
+		"b": "${env:MISSING:fallback}",
+	}
+	if err := newTestResolver().ExpandAll(kvMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kvMap["a"] != "env-host" {
+		t.Fatalf("expected env lookup to expand, got %v", kvMap["a"])
+	}
+	if kvMap["b"] != "fallback" {
+		t.Fatalf("expected missing env var to fall back to default, got %v", kvMap["b"])
+	}
+}
+
+func TestExpandAll_EnvMissingWithoutDefaultErrors(t *testing.T) {
+	kvMap := map[string]interface{}{"a": "${env:MISSING}"}
+	if err := newTestResolver().ExpandAll(kvMap); err == nil {
+		t.Fatalf("expected error for missing env var with no default")
+	}
+}
+
+func TestExpandAll_Scope(t *testing.T) {
+	kvMap := map[string]interface{}{"a": "${scope:svc/default/port}"}
+	if err := newTestResolver().ExpandAll(kvMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kvMap["a"] != "8080" {
+		t.Fatalf("expected scope lookup to expand, got %v", kvMap["a"])
+	}
+}
+
+func TestExpandAll_ScopeNotFoundErrors(t *testing.T) {
+	kvMap := map[string]interface{}{"a": "${scope:svc/default/missing}"}
+	if err := newTestResolver().ExpandAll(kvMap); err == nil {
+		t.Fatalf("expected error for unresolved scoped variable")
+	}
+}
+
+// A plain-key reference cycle is left as literal text rather than failing
+// the whole Poll/Watch response: real YAMLs often carry `${...}` syntax
+// never meant for server-side resolution.
+func TestExpandAll_CycleLeftLiteral(t *testing.T) {
+	kvMap := map[string]interface{}{
+		"a": "${b}",
+		"b": "${a}",
+	}
+	if err := newTestResolver().ExpandAll(kvMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kvMap["a"] != "${b}" {
+		t.Fatalf("expected cyclic reference to be left literal, got %v", kvMap["a"])
+	}
+}
+
+func TestExpandAll_MaxDepthExceededLeftLiteral(t *testing.T) {
+	r := newTestResolver()
+	r.MaxDepth = 1
+	kvMap := map[string]interface{}{
+		"a": "${b}",
+		"b": "${c}",
+		"c": "value-c",
+	}
+	if err := r.ExpandAll(kvMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kvMap["a"] != "${b}" {
+		t.Fatalf("expected unresolved chain to be left literal, got %v", kvMap["a"])
+	}
+}
+
+func TestExpandAll_KeyNotFoundLeftLiteral(t *testing.T) {
+	kvMap := map[string]interface{}{"a": "${missing}"}
+	if err := newTestResolver().ExpandAll(kvMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kvMap["a"] != "${missing}" {
+		t.Fatalf("expected unresolved key to be left literal, got %v", kvMap["a"])
+	}
+}
@@ -0,0 +1,33 @@
+// Package embed holds the register-server's own runtime configuration,
+// as opposed to the per-service config it serves from ConfigMaps.
+package embed
+
+import "github.com/choerodon/go-register-server/pkg/config/crypto"
+
+// ConfigServerConfig configures ConfigServiceImpl's Save/Poll behavior.
+type ConfigServerConfig struct {
+	// Log, when true, logs the full resolved config on every Poll.
+	Log bool
+	// GatewayNames lists the services whose zuul routes live in the shared
+	// RouteConfigMap instead of their own ConfigMap.
+	GatewayNames []string
+	// Crypto selects and configures the Decryptor backend used for
+	// `{cipher}...` property values.
+	Crypto crypto.Config
+	// WatchTimeoutSeconds bounds how long a long-poll Watch request (or one
+	// SSE send cycle) blocks before returning 304/a keep-alive comment.
+	// Defaults to 30s when unset.
+	WatchTimeoutSeconds int
+	// ClustersFile points at a mounted clusters.yaml listing the additional
+	// clusters to federate Save/Poll across. Multi-cluster federation is
+	// disabled when empty.
+	ClustersFile string
+}
+
+// EnvConfig is the root of register-server's own runtime configuration.
+type EnvConfig struct {
+	ConfigServer ConfigServerConfig
+}
+
+// Env is populated at startup from flags/environment variables.
+var Env = &EnvConfig{}
@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/choerodon/go-register-server/pkg/api/entity"
+	"github.com/choerodon/go-register-server/pkg/utils"
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+)
+
+// localClusterStore adapts the existing in-cluster ConfigMapOperator to
+// ConfigStore, so CompositeStore can treat the local cluster the same way
+// as every remote one.
+type localClusterStore struct {
+	ConfigMapOperator
+}
+
+func (localClusterStore) ClusterID() string {
+	return "local"
+}
+
+// CompositeStore fans reads and writes out across the local cluster and any
+// remote clusters configured in clusters.yaml, and runs a background
+// reconciler that keeps each cluster's health and last-sync time up to date
+// for GET /config/clusters.
+type CompositeStore struct {
+	mu      sync.RWMutex
+	local   ConfigStore
+	remotes map[string]ConfigStore
+	health  map[string]*ClusterHealth
+}
+
+// NewCompositeStore wraps local (the in-cluster operator) and remotes (one
+// per clusters.yaml entry) and starts the background health reconciler.
+func NewCompositeStore(local ConfigMapOperator, remotes []ConfigStore, reconcileInterval time.Duration) *CompositeStore {
+	cs := &CompositeStore{
+		local:   localClusterStore{local},
+		remotes: make(map[string]ConfigStore, len(remotes)),
+		health:  make(map[string]*ClusterHealth, len(remotes)+1),
+	}
+	for _, r := range remotes {
+		cs.remotes[r.ClusterID()] = r
+	}
+	if reconcileInterval <= 0 {
+		reconcileInterval = time.Minute
+	}
+	go cs.reconcileLoop(reconcileInterval)
+	return cs
+}
+
+// StoreFor returns the ConfigStore for clusterID, falling back to the local
+// in-cluster store when clusterID is empty or unknown. Callers use this to
+// honor the X-Cluster-Id header on Poll and the ?cluster= query parameter.
+func (cs *CompositeStore) StoreFor(clusterID string) ConfigStore {
+	if clusterID == "" {
+		return cs.local
+	}
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if s, ok := cs.remotes[clusterID]; ok {
+		return s
+	}
+	return cs.local
+}
+
+func (cs *CompositeStore) all() []ConfigStore {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	stores := make([]ConfigStore, 0, len(cs.remotes)+1)
+	stores = append(stores, cs.local)
+	for _, r := range cs.remotes {
+		stores = append(stores, r)
+	}
+	return stores
+}
+
+// Broadcast writes dto's yaml to every configured cluster transactionally:
+// each cluster's current ConfigMap is snapshotted before the write, and if
+// any cluster's write fails, every cluster already written is restored from
+// its snapshot.
+func (cs *CompositeStore) Broadcast(dto *entity.SaveConfigDTO) error {
+	type writeRecord struct {
+		store    ConfigStore
+		snapshot *v1.ConfigMap
+	}
+	var written []writeRecord
+	for _, store := range cs.all() {
+		snapshot := store.QueryConfigMap(dto.Service, dto.Namespace)
+		if err := writeToStore(store, dto); err != nil {
+			for _, w := range written {
+				rollbackStore(w.store, dto, w.snapshot)
+			}
+			return fmt.Errorf("k8s: broadcast to cluster %s failed: %w", store.ClusterID(), err)
+		}
+		written = append(written, writeRecord{store: store, snapshot: snapshot})
+	}
+	return nil
+}
+
+func writeToStore(store ConfigStore, dto *entity.SaveConfigDTO) error {
+	if store.QueryConfigMap(dto.Service, dto.Namespace) == nil {
+		_, err := store.CreateConfigMap(dto)
+		return err
+	}
+	_, err := store.UpdateConfigMap(dto)
+	return err
+}
+
+// rollbackStore restores a cluster's ConfigMap to its pre-broadcast state.
+// When snapshot is nil, writeToStore created the ConfigMap as part of this
+// broadcast, so rolling back means deleting it rather than updating it back
+// to a state that never existed. Failures are logged rather than propagated:
+// by this point the broadcast has already failed and the caller is reporting
+// that error to the client.
+func rollbackStore(store ConfigStore, dto *entity.SaveConfigDTO, snapshot *v1.ConfigMap) {
+	if snapshot == nil {
+		if err := store.DeleteConfigMap(dto.Service, dto.Namespace); err != nil {
+			glog.Warningf("k8s: rollback cluster %s failed to delete stranded configMap", store.ClusterID(), err)
+		}
+		return
+	}
+	restore := &entity.SaveConfigDTO{
+		Service:      dto.Service,
+		Profile:      dto.Profile,
+		Namespace:    dto.Namespace,
+		UpdatePolicy: entity.UpdatePolicyOverride,
+		Yaml:         snapshot.Data[utils.ConfigMapProfileKey(dto.Profile)],
+	}
+	if _, err := store.UpdateConfigMap(restore); err != nil {
+		glog.Warningf("k8s: rollback cluster %s failed", store.ClusterID(), err)
+	}
+}
+
+func (cs *CompositeStore) reconcileLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		cs.reconcileOnce()
+	}
+}
+
+func (cs *CompositeStore) reconcileOnce() {
+	for _, store := range cs.all() {
+		id := store.ClusterID()
+		health := &ClusterHealth{ClusterID: id, LastSync: now()}
+		if store.QueryConfigMapByName(entity.RouteConfigMap) == nil {
+			health.LastError = "route configMap not reachable"
+		}
+		health.Healthy = health.LastError == ""
+		cs.mu.Lock()
+		cs.health[id] = health
+		cs.mu.Unlock()
+	}
+}
+
+// Health returns the last-reconciled health snapshot for every cluster.
+func (cs *CompositeStore) Health() []*ClusterHealth {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	result := make([]*ClusterHealth, 0, len(cs.health))
+	for _, h := range cs.health {
+		result = append(result, h)
+	}
+	return result
+}
+
+func now() time.Time {
+	return time.Now()
+}
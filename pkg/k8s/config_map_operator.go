@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"sync"
+
+	"github.com/choerodon/go-register-server/pkg/api/entity"
+	"github.com/choerodon/go-register-server/pkg/utils"
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ConfigMapOperator is the local-cluster equivalent of ConfigStore, used
+// wherever register-server talks to the Kubernetes cluster it runs in.
+type ConfigMapOperator interface {
+	QueryConfigMap(service string, namespace string) *v1.ConfigMap
+	QueryConfigMapByName(name string) *v1.ConfigMap
+	CreateConfigMap(dto *entity.SaveConfigDTO) (*v1.ConfigMap, error)
+	UpdateConfigMap(dto *entity.SaveConfigDTO) (*v1.ConfigMap, error)
+	// DeleteConfigMap removes a ConfigMap, used to roll back a Broadcast that
+	// created one on a cluster before failing on another.
+	DeleteConfigMap(service string, namespace string) error
+	// WatchConfigMap returns a channel that receives a value every time the
+	// named ConfigMap is added or updated, and a stop func to unsubscribe.
+	// The in-cluster operator serves every subscriber from one shared
+	// informer; remoteClusterStore opens one watch per call.
+	WatchConfigMap(name string) (<-chan struct{}, func())
+}
+
+// configMapOperatorImpl talks to the cluster register-server itself runs in,
+// via the in-cluster service account, and fans ConfigMap change events out
+// to Watch subscribers through one shared informer.
+type configMapOperatorImpl struct {
+	clientset *kubernetes.Clientset
+
+	mu       sync.Mutex
+	watchers map[string][]chan struct{}
+}
+
+// NewConfigMapOperator builds the local ConfigMapOperator and starts its
+// shared ConfigMap informer.
+func NewConfigMapOperator() ConfigMapOperator {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		glog.Warningf("k8s: not running in-cluster, configMap operations will fail", err)
+		return &configMapOperatorImpl{watchers: make(map[string][]chan struct{})}
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		glog.Warningf("k8s: build in-cluster clientset failed, configMap operations will fail", err)
+		return &configMapOperatorImpl{watchers: make(map[string][]chan struct{})}
+	}
+	op := &configMapOperatorImpl{
+		clientset: clientset,
+		watchers:  make(map[string][]chan struct{}),
+	}
+	op.startInformer()
+	return op
+}
+
+// startInformer sets up the single shared informer every WatchConfigMap
+// subscription rides on, rather than each caller opening its own watch
+// against the API server.
+func (o *configMapOperatorImpl) startInformer() {
+	factory := informers.NewSharedInformerFactory(o.clientset, 0)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { o.notify(obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) { o.notify(newObj) },
+	})
+	stopCh := make(chan struct{})
+	go informer.Run(stopCh)
+}
+
+func (o *configMapOperatorImpl) notify(obj interface{}) {
+	configMap, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, ch := range o.watchers[configMap.Name] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (o *configMapOperatorImpl) WatchConfigMap(name string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	o.mu.Lock()
+	o.watchers[name] = append(o.watchers[name], ch)
+	o.mu.Unlock()
+
+	stop := func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		subs := o.watchers[name]
+		for i, existing := range subs {
+			if existing == ch {
+				o.watchers[name] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+	return ch, stop
+}
+
+func (o *configMapOperatorImpl) QueryConfigMap(service string, namespace string) *v1.ConfigMap {
+	configMap, err := o.clientset.CoreV1().ConfigMaps(namespace).Get(service, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	return configMap
+}
+
+func (o *configMapOperatorImpl) QueryConfigMapByName(name string) *v1.ConfigMap {
+	configMaps, err := o.clientset.CoreV1().ConfigMaps(metav1.NamespaceAll).List(metav1.ListOptions{FieldSelector: "metadata.name=" + name})
+	if err != nil || len(configMaps.Items) == 0 {
+		return nil
+	}
+	return &configMaps.Items[0]
+}
+
+func (o *configMapOperatorImpl) CreateConfigMap(dto *entity.SaveConfigDTO) (*v1.ConfigMap, error) {
+	configMap := buildConfigMap(dto)
+	return o.clientset.CoreV1().ConfigMaps(dto.Namespace).Create(configMap)
+}
+
+func (o *configMapOperatorImpl) UpdateConfigMap(dto *entity.SaveConfigDTO) (*v1.ConfigMap, error) {
+	existing, err := o.clientset.CoreV1().ConfigMaps(dto.Namespace).Get(dto.Service, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existing.Data[utils.ConfigMapProfileKey(dto.Profile)] = dto.Yaml
+	return o.clientset.CoreV1().ConfigMaps(dto.Namespace).Update(existing)
+}
+
+func (o *configMapOperatorImpl) DeleteConfigMap(service string, namespace string) error {
+	return o.clientset.CoreV1().ConfigMaps(namespace).Delete(service, &metav1.DeleteOptions{})
+}
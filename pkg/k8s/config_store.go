@@ -0,0 +1,25 @@
+package k8s
+
+import (
+	"time"
+)
+
+// ConfigStore generalizes ConfigMapOperator to more than one Kubernetes
+// cluster: remoteClusterStore talks to a single additional cluster loaded
+// from clusters.yaml, and CompositeStore fans reads/writes out across every
+// configured cluster, keyed by cluster id.
+type ConfigStore interface {
+	ConfigMapOperator
+	// ClusterID identifies which cluster this store talks to, as used in
+	// SaveConfigDTO.Cluster and the ?cluster= query parameter.
+	ClusterID() string
+}
+
+// ClusterHealth is returned by GET /config/clusters, one entry per
+// configured cluster, driven by CompositeStore's background reconciler.
+type ClusterHealth struct {
+	ClusterID string    `json:"clusterId"`
+	Healthy   bool      `json:"healthy"`
+	LastSync  time.Time `json:"lastSync"`
+	LastError string    `json:"lastError,omitempty"`
+}
@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/choerodon/go-register-server/pkg/api/entity"
+	"github.com/choerodon/go-register-server/pkg/utils"
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// clusterEntry is one entry of the mounted clusters.yaml file: a cluster id
+// paired with the kubeconfig used to reach it.
+type clusterEntry struct {
+	ID             string `json:"id" yaml:"id"`
+	KubeconfigPath string `json:"kubeconfigPath" yaml:"kubeconfigPath"`
+}
+
+type clustersFile struct {
+	Clusters []clusterEntry `json:"clusters" yaml:"clusters"`
+}
+
+// remoteClusterStore is a ConfigStore backed by a Kubernetes cluster other
+// than the one register-server itself runs in.
+type remoteClusterStore struct {
+	id        string
+	clientset *kubernetes.Clientset
+}
+
+// LoadRemoteClusterStores reads clusters.yaml (mounted into the pod) and
+// builds one remoteClusterStore per entry.
+func LoadRemoteClusterStores(clustersYamlPath string) ([]ConfigStore, error) {
+	raw, err := ioutil.ReadFile(clustersYamlPath)
+	if err != nil {
+		return nil, fmt.Errorf("k8s: read clusters.yaml: %w", err)
+	}
+	var parsed clustersFile
+	if err := yaml.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("k8s: parse clusters.yaml: %w", err)
+	}
+	stores := make([]ConfigStore, 0, len(parsed.Clusters))
+	for _, c := range parsed.Clusters {
+		store, err := newRemoteClusterStore(c)
+		if err != nil {
+			glog.Warningf("k8s: skip cluster %s", c.ID, err)
+			continue
+		}
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+func newRemoteClusterStore(c clusterEntry) (*remoteClusterStore, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", c.KubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteClusterStore{id: c.ID, clientset: clientset}, nil
+}
+
+func buildConfigMap(dto *entity.SaveConfigDTO) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dto.Service,
+			Namespace: dto.Namespace,
+		},
+		Data: map[string]string{
+			utils.ConfigMapProfileKey(dto.Profile): dto.Yaml,
+		},
+	}
+}
+
+func (r *remoteClusterStore) ClusterID() string {
+	return r.id
+}
+
+func (r *remoteClusterStore) QueryConfigMap(service string, namespace string) *v1.ConfigMap {
+	configMap, err := r.clientset.CoreV1().ConfigMaps(namespace).Get(service, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	return configMap
+}
+
+func (r *remoteClusterStore) QueryConfigMapByName(name string) *v1.ConfigMap {
+	configMaps, err := r.clientset.CoreV1().ConfigMaps(metav1.NamespaceAll).List(metav1.ListOptions{FieldSelector: "metadata.name=" + name})
+	if err != nil || len(configMaps.Items) == 0 {
+		return nil
+	}
+	return &configMaps.Items[0]
+}
+
+func (r *remoteClusterStore) CreateConfigMap(dto *entity.SaveConfigDTO) (*v1.ConfigMap, error) {
+	configMap := buildConfigMap(dto)
+	return r.clientset.CoreV1().ConfigMaps(dto.Namespace).Create(configMap)
+}
+
+func (r *remoteClusterStore) UpdateConfigMap(dto *entity.SaveConfigDTO) (*v1.ConfigMap, error) {
+	existing, err := r.clientset.CoreV1().ConfigMaps(dto.Namespace).Get(dto.Service, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existing.Data[utils.ConfigMapProfileKey(dto.Profile)] = dto.Yaml
+	return r.clientset.CoreV1().ConfigMaps(dto.Namespace).Update(existing)
+}
+
+func (r *remoteClusterStore) DeleteConfigMap(service string, namespace string) error {
+	return r.clientset.CoreV1().ConfigMaps(namespace).Delete(service, &metav1.DeleteOptions{})
+}
+
+func (r *remoteClusterStore) WatchConfigMap(name string) (<-chan struct{}, func()) {
+	events := make(chan struct{})
+	stop := make(chan struct{})
+	watcher, err := r.clientset.CoreV1().ConfigMaps(metav1.NamespaceAll).Watch(metav1.ListOptions{FieldSelector: "metadata.name=" + name})
+	if err != nil {
+		glog.Warningf("k8s: watch configMap %s on cluster %s failed", name, r.id, err)
+		return events, func() {}
+	}
+	go func() {
+		defer watcher.Stop()
+		for {
+			select {
+			case ev, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				if ev.Type != watch.Added && ev.Type != watch.Modified {
+					continue
+				}
+				select {
+				case events <- struct{}{}:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return events, func() { close(stop) }
+}